@@ -0,0 +1,307 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/protocol/bncs"
+	"github.com/nielsAD/gowarcraft3/protocol/capi"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// watchContext arranges for abort to be called with a long-past deadline when ctx is done before
+// the returned stop function is called, so a blocking Read/Write relying on that deadline returns
+// early instead of ignoring ctx cancellation. stop always calls abort once more with the zero
+// Time to clear that deadline again, so a cancelled ctx never leaves the conn stuck with a
+// deadline in the past for later calls.
+func watchContext(ctx context.Context, abort func(time.Time)) (stop func()) {
+	if ctx.Done() == nil {
+		return func() {}
+	}
+
+	var done = make(chan struct{})
+	var exited = make(chan struct{})
+	go func() {
+		defer close(exited)
+		select {
+		case <-ctx.Done():
+			abort(time.Unix(0, 0))
+		case <-done:
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-exited
+		abort(time.Time{})
+	}
+}
+
+// ctxErr returns ctx.Err() if ctx is why err occurred, or err otherwise
+func ctxErr(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// SendContext sends pkt to addr like Send, aborting early if ctx is done before the send completes
+func (c *W3GSPacketConn) SendContext(ctx context.Context, addr net.Addr, pkt w3gs.Packet) (int, error) {
+	var conn = c.Conn()
+	if conn == nil {
+		return 0, wrapConnErr("SendContext", addr, ErrConnClosed)
+	}
+
+	var stop = watchContext(ctx, func(t time.Time) { conn.SetWriteDeadline(t) })
+	defer stop()
+
+	n, err := c.Send(addr, pkt)
+	return n, ctxErr(ctx, err)
+}
+
+// NextPacketContext waits for the next packet like NextPacket, aborting early if ctx is done first
+func (c *W3GSPacketConn) NextPacketContext(ctx context.Context) (w3gs.Packet, net.Addr, error) {
+	var conn = c.Conn()
+	if conn == nil {
+		return nil, nil, wrapConnErr("NextPacketContext", nil, ErrConnClosed)
+	}
+
+	var stop = watchContext(ctx, func(t time.Time) { conn.SetReadDeadline(t) })
+	defer stop()
+
+	pkt, addr, err := c.NextPacket(0)
+	return pkt, addr, ctxErr(ctx, err)
+}
+
+// RunContext runs like Run, but returns nil instead of ctx.Err() when ctx is cancelled, so
+// callers can treat context cancellation as a graceful shutdown rather than a fatal error
+func (c *W3GSPacketConn) RunContext(ctx context.Context, f Emitter, timeout time.Duration) error {
+	var conn = c.Conn()
+	if conn == nil {
+		return wrapConnErr("RunContext", nil, ErrConnClosed)
+	}
+
+	var stop = watchContext(ctx, func(t time.Time) { conn.SetDeadline(t) })
+	defer stop()
+
+	var err = c.Run(f, timeout)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// SendContext sends pkt like Send, aborting early if ctx is done before the send completes
+func (c *W3GSConn) SendContext(ctx context.Context, pkt w3gs.Packet) (int, error) {
+	var conn = c.Conn()
+	if conn == nil {
+		return 0, wrapConnErr("SendContext", nil, ErrConnClosed)
+	}
+
+	var stop = watchContext(ctx, func(t time.Time) { conn.SetWriteDeadline(t) })
+	defer stop()
+
+	n, err := c.Send(pkt)
+	return n, ctxErr(ctx, err)
+}
+
+// NextPacketContext waits for the next packet like NextPacket, aborting early if ctx is done first
+func (c *W3GSConn) NextPacketContext(ctx context.Context) (w3gs.Packet, error) {
+	var conn = c.Conn()
+	if conn == nil {
+		return nil, wrapConnErr("NextPacketContext", nil, ErrConnClosed)
+	}
+
+	var stop = watchContext(ctx, func(t time.Time) { conn.SetReadDeadline(t) })
+	defer stop()
+
+	pkt, err := c.NextPacket(0)
+	return pkt, ctxErr(ctx, err)
+}
+
+// RunContext runs like Run, but returns nil instead of ctx.Err() when ctx is cancelled, so
+// callers can treat context cancellation as a graceful shutdown rather than a fatal error
+func (c *W3GSConn) RunContext(ctx context.Context, f Emitter, timeout time.Duration) error {
+	var conn = c.Conn()
+	if conn == nil {
+		return wrapConnErr("RunContext", nil, ErrConnClosed)
+	}
+
+	var stop = watchContext(ctx, func(t time.Time) { conn.SetDeadline(t) })
+	defer stop()
+
+	var err = c.Run(f, timeout)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// SendContext sends pkt like Send, aborting early if ctx is done before the send completes
+func (c *BNCSConn) SendContext(ctx context.Context, pkt bncs.Packet) (int, error) {
+	var conn = c.Conn()
+	if conn == nil {
+		return 0, wrapConnErr("SendContext", nil, ErrConnClosed)
+	}
+
+	var stop = watchContext(ctx, func(t time.Time) { conn.SetWriteDeadline(t) })
+	defer stop()
+
+	n, err := c.Send(pkt)
+	return n, ctxErr(ctx, err)
+}
+
+// SendRLContext sends pkt like SendRL, but also aborts the rate-limit wait early if ctx is done first
+func (c *BNCSConn) SendRLContext(ctx context.Context, pkt bncs.Packet) (int, error) {
+	c.lmut.Lock()
+	var now = time.Now()
+	var wait time.Duration
+	if now.Before(c.lnxt) {
+		wait = c.lnxt.Sub(now)
+	}
+	c.lmut.Unlock()
+
+	if wait > 0 {
+		var timer = time.NewTimer(wait)
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	n, err := c.SendContext(ctx, pkt)
+
+	if n > 0 {
+		c.lmut.Lock()
+		if rl := c.rl; rl != nil {
+			c.lnxt = time.Now().Add(rl.Reserve(n))
+		}
+		c.lmut.Unlock()
+	}
+
+	return n, err
+}
+
+// NextClientPacketContext waits for the next client packet like NextClientPacket, aborting early
+// if ctx is done first
+func (c *BNCSConn) NextClientPacketContext(ctx context.Context) (bncs.Packet, error) {
+	var conn = c.Conn()
+	if conn == nil {
+		return nil, wrapConnErr("NextClientPacketContext", nil, ErrConnClosed)
+	}
+
+	var stop = watchContext(ctx, func(t time.Time) { conn.SetReadDeadline(t) })
+	defer stop()
+
+	pkt, err := c.NextClientPacket(0)
+	return pkt, ctxErr(ctx, err)
+}
+
+// NextServerPacketContext waits for the next server packet like NextServerPacket, aborting early
+// if ctx is done first
+func (c *BNCSConn) NextServerPacketContext(ctx context.Context) (bncs.Packet, error) {
+	var conn = c.Conn()
+	if conn == nil {
+		return nil, wrapConnErr("NextServerPacketContext", nil, ErrConnClosed)
+	}
+
+	var stop = watchContext(ctx, func(t time.Time) { conn.SetReadDeadline(t) })
+	defer stop()
+
+	pkt, err := c.NextServerPacket(0)
+	return pkt, ctxErr(ctx, err)
+}
+
+// RunServerContext runs like RunServer, but returns nil instead of ctx.Err() when ctx is
+// cancelled, so callers can treat context cancellation as a graceful shutdown rather than a
+// fatal error
+func (c *BNCSConn) RunServerContext(ctx context.Context, f Emitter, timeout time.Duration) error {
+	var conn = c.Conn()
+	if conn == nil {
+		return wrapConnErr("RunServerContext", nil, ErrConnClosed)
+	}
+
+	var stop = watchContext(ctx, func(t time.Time) { conn.SetDeadline(t) })
+	defer stop()
+
+	var err = c.RunServer(f, timeout)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// RunClientContext runs like RunClient, but returns nil instead of ctx.Err() when ctx is
+// cancelled, so callers can treat context cancellation as a graceful shutdown rather than a
+// fatal error
+func (c *BNCSConn) RunClientContext(ctx context.Context, f Emitter, timeout time.Duration) error {
+	var conn = c.Conn()
+	if conn == nil {
+		return wrapConnErr("RunClientContext", nil, ErrConnClosed)
+	}
+
+	var stop = watchContext(ctx, func(t time.Time) { conn.SetDeadline(t) })
+	defer stop()
+
+	var err = c.RunClient(f, timeout)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// SendContext sends pkt like Send, aborting early if ctx is done before the send completes
+func (c *CAPIConn) SendContext(ctx context.Context, pkt *capi.Packet) error {
+	var conn = c.Conn()
+	if conn == nil {
+		return wrapConnErr("SendContext", nil, ErrConnClosed)
+	}
+
+	var stop = watchContext(ctx, func(t time.Time) { conn.SetWriteDeadline(t) })
+	defer stop()
+
+	return ctxErr(ctx, c.Send(pkt))
+}
+
+// NextPacketContext waits for the next packet like NextPacket, aborting early if ctx is done first
+func (c *CAPIConn) NextPacketContext(ctx context.Context) (*capi.Packet, error) {
+	var conn = c.Conn()
+	if conn == nil {
+		return nil, wrapConnErr("NextPacketContext", nil, ErrConnClosed)
+	}
+
+	var stop = watchContext(ctx, func(t time.Time) { conn.SetReadDeadline(t) })
+	defer stop()
+
+	pkt, err := c.NextPacket(0)
+	return pkt, ctxErr(ctx, err)
+}
+
+// RunContext runs like Run, but returns nil instead of ctx.Err() when ctx is cancelled, so
+// callers can treat context cancellation as a graceful shutdown rather than a fatal error
+func (c *CAPIConn) RunContext(ctx context.Context, f Emitter, timeout time.Duration) error {
+	var conn = c.Conn()
+	if conn == nil {
+		return wrapConnErr("RunContext", nil, ErrConnClosed)
+	}
+
+	var stop = watchContext(ctx, func(t time.Time) {
+		conn.SetReadDeadline(t)
+		conn.SetWriteDeadline(t)
+	})
+	defer stop()
+
+	var err = c.Run(f, timeout)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
@@ -5,6 +5,7 @@
 package network
 
 import (
+	"errors"
 	"io"
 	"net"
 	"os"
@@ -13,6 +14,45 @@ import (
 	"github.com/gorilla/websocket"
 )
 
+// ErrConnClosed is wrapped (in a *ConnError) by Send, NextPacket, and their protocol-specific
+// equivalents when called against a Conn with no underlying connection set. Use
+// errors.Is(err, network.ErrConnClosed) instead of IsConnClosedError when err is known to
+// originate from this package.
+var ErrConnClosed = errors.New("network: connection closed")
+
+// ConnError wraps err with the operation and (if known) remote address of the Conn it occurred
+// on. Send, SendRL, TrySendRL, and NextPacket (and their protocol-specific equivalents) return a
+// *ConnError so callers can recover that context with errors.As while still being able to
+// errors.Is through to the underlying cause. TrySendRL returns ErrRateLimited itself, unwrapped.
+type ConnError struct {
+	Op   string
+	Addr net.Addr
+	Err  error
+}
+
+func (e *ConnError) Error() string {
+	var s = "network: " + e.Op
+	if e.Addr != nil {
+		s += " " + e.Addr.String()
+	}
+	if e.Err != nil {
+		s += ": " + e.Err.Error()
+	}
+	return s
+}
+
+// Unwrap returns the wrapped error
+func (e *ConnError) Unwrap() error {
+	return e.Err
+}
+
+func wrapConnErr(op string, addr net.Addr, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &ConnError{Op: op, Addr: addr, Err: err}
+}
+
 // AsyncError keeps track of where a non-fatal asynchronous error orignated
 type AsyncError struct {
 	Src string
@@ -26,6 +66,11 @@ func (e *AsyncError) Error() string {
 	return e.Src + ":" + e.Err.Error()
 }
 
+// Unwrap returns the wrapped error
+func (e *AsyncError) Unwrap() error {
+	return e.Err
+}
+
 // Temporary error
 func (e *AsyncError) Temporary() bool {
 	return IsTemporaryError(e.Err)
@@ -41,6 +86,8 @@ func UnnestError(err error) error {
 	switch e := err.(type) {
 	case *AsyncError:
 		return UnnestError(e.Err)
+	case *ConnError:
+		return UnnestError(e.Err)
 	case *net.OpError:
 		return UnnestError(e.Err)
 	case *os.SyscallError:
@@ -54,20 +101,15 @@ func UnnestError(err error) error {
 	}
 }
 
-// IsUseClosedNetworkError checks if net.error is poll.ErrNetClosed
+// IsUseClosedNetworkError checks if err indicates use of an already-closed network connection
 func IsUseClosedNetworkError(err error) bool {
-	return err != nil && err.Error() == "use of closed network connection"
+	return errors.Is(err, net.ErrClosed)
 }
 
 // IsSysCallError checks if error is one of syscall.Errno
 func IsSysCallError(err error, errno ...syscall.Errno) bool {
-	err = UnnestError(err)
-	if err == nil {
-		return false
-	}
-
-	n, ok := err.(syscall.Errno)
-	if !ok {
+	var n syscall.Errno
+	if !errors.As(err, &n) {
 		return false
 	}
 
@@ -85,8 +127,6 @@ const WSAECONNREFUSED = 10061
 
 // IsConnRefusedError checks if err indicates a refused connection
 func IsConnRefusedError(err error) bool {
-	err = UnnestError(err)
-
 	if IsSysCallError(err, syscall.ECONNREFUSED, WSAECONNREFUSED) {
 		return true
 	}
@@ -111,8 +151,7 @@ const WSAESHUTDOWN = 10058
 
 // IsConnClosedError checks if err indicates a closed connection
 func IsConnClosedError(err error) bool {
-	err = UnnestError(err)
-	if err == io.EOF || IsUseClosedNetworkError(err) {
+	if errors.Is(err, ErrConnClosed) || errors.Is(err, io.EOF) || IsUseClosedNetworkError(err) {
 		return true
 	}
 
@@ -130,7 +169,7 @@ func IsConnClosedError(err error) bool {
 		return true
 	}
 
-	return err == websocket.ErrCloseSent || websocket.IsUnexpectedCloseError(err)
+	return errors.Is(err, websocket.ErrCloseSent) || websocket.IsUnexpectedCloseError(err)
 }
 
 type temporary interface {
@@ -143,11 +182,8 @@ func IsTemporaryError(err error) bool {
 		return false
 	}
 
-	t, ok := err.(temporary)
-	if !ok {
-		t, ok = UnnestError(err).(temporary)
-	}
-	if ok {
+	var t temporary
+	if errors.As(err, &t) {
 		return t.Temporary()
 	}
 
@@ -164,9 +200,10 @@ func IsTimeoutError(err error) bool {
 		return false
 	}
 
-	t, ok := err.(timeout)
-	if !ok {
-		t, ok = UnnestError(err).(timeout)
+	var t timeout
+	if errors.As(err, &t) {
+		return t.Timeout()
 	}
-	return ok && t.Timeout()
+
+	return false
 }
@@ -0,0 +1,115 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package eventstream
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/network"
+)
+
+// ClientBackoff configures how long Client waits between reconnect attempts
+var ClientBackoff = 2 * time.Second
+
+// Client subscribes to a Server's SSE endpoint and reconnects automatically on temporary errors
+type Client struct {
+	// URL of the Server's SSE endpoint, e.g. "http://localhost:8080/events"
+	URL string
+
+	// Types optionally filters the subscription to a subset of event types
+	Types []string
+}
+
+// NewClient returns a Client that streams Events from url
+func NewClient(url string) *Client {
+	return &Client{URL: url}
+}
+
+// Run connects to c.URL and sends every received Event on the returned channel, reconnecting
+// with ClientBackoff between attempts whenever the connection fails with a temporary error. Run
+// returns immediately and streams in the background until ctx is cancelled or the stream ends
+// with a non-temporary (or nil) error, which is then sent on the error channel; the event
+// channel is closed when streaming stops.
+func (c *Client) Run(ctx context.Context) (<-chan Event, <-chan error) {
+	var events = make(chan Event)
+	var errs = make(chan error, 1)
+
+	go func() {
+		defer close(events)
+
+		for {
+			err := c.stream(ctx, events)
+			if ctx.Err() != nil {
+				return
+			}
+			if err == nil || !network.IsTemporaryError(err) {
+				errs <- err
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(ClientBackoff):
+			}
+		}
+	}()
+
+	return events, errs
+}
+
+func (c *Client) stream(ctx context.Context, events chan<- Event) error {
+	var url = c.URL
+	if len(c.Types) > 0 {
+		var q = make([]string, len(c.Types))
+		for i, t := range c.Types {
+			q[i] = "type=" + t
+		}
+		url += "?" + strings.Join(q, "&")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("eventstream: unexpected status %s", res.Status)
+	}
+
+	var s = bufio.NewScanner(res.Body)
+	for s.Scan() {
+		var line = s.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+
+		var ev Event
+		if err := json.Unmarshal([]byte(line[len("data: "):]), &ev); err != nil {
+			continue
+		}
+
+		select {
+		case events <- ev:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	return s.Err()
+}
@@ -0,0 +1,234 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+// Package eventstream exposes every event fired through a network.Emitter (w3gs/bncs/capi
+// packets, network.RunStart/network.RunStop, and network.AsyncError) to external observers as a
+// live HTTP stream, so bots and game servers built on this module can be watched without
+// embedding Go code.
+package eventstream
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/nielsAD/gowarcraft3/network"
+)
+
+// Event is the stable JSON schema published for every packet/event fired through a Server
+type Event struct {
+	Type    string        `json:"type"`
+	Time    time.Time     `json:"time"`
+	Payload interface{}   `json:"payload"`
+	Extra   []interface{} `json:"extra,omitempty"`
+}
+
+// clientQueueSize bounds how far a single client may lag behind before its messages are dropped
+const clientQueueSize = 64
+
+// client is a single subscriber connected to the event stream
+type client struct {
+	types  map[string]struct{}
+	queue  chan []byte
+	closed chan struct{}
+}
+
+func newClient(types []string) *client {
+	var t map[string]struct{}
+	if len(types) > 0 {
+		t = make(map[string]struct{}, len(types))
+		for _, s := range types {
+			t[s] = struct{}{}
+		}
+	}
+
+	return &client{
+		types:  t,
+		queue:  make(chan []byte, clientQueueSize),
+		closed: make(chan struct{}),
+	}
+}
+
+func (c *client) subscribed(typ string) bool {
+	if len(c.types) == 0 {
+		return true
+	}
+	_, ok := c.types[typ]
+	return ok
+}
+
+// Server republishes every event fired through a wrapped network.Emitter to connected HTTP
+// clients over Server-Sent Events or WebSocket JSON, with per-client backpressure and
+// per-packet-type subscription filters. Server embeds the wrapped Emitter, so it can be passed
+// anywhere a network.Emitter is expected, e.g. conn.Run(srv, timeout).
+type Server struct {
+	network.Emitter
+
+	mut     sync.RWMutex
+	clients map[*client]struct{}
+
+	upgrader websocket.Upgrader
+}
+
+// New returns a Server that republishes every event fired through e
+func New(e network.Emitter) *Server {
+	return &Server{
+		Emitter: e,
+		clients: make(map[*client]struct{}),
+		upgrader: websocket.Upgrader{
+			CheckOrigin: func(*http.Request) bool { return true },
+		},
+	}
+}
+
+// Fire forwards ev to the wrapped Emitter and publishes it to all subscribed clients
+func (s *Server) Fire(ev network.EventArg, opt ...network.EventArg) bool {
+	var handled = s.Emitter.Fire(ev, opt...)
+	s.publish(ev, opt...)
+	return handled
+}
+
+func eventType(ev network.EventArg) string {
+	var t = reflect.TypeOf(ev)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil {
+		return "nil"
+	}
+	return t.String()
+}
+
+func (s *Server) publish(ev network.EventArg, opt ...network.EventArg) {
+	var msg = Event{
+		Type:    eventType(ev),
+		Time:    time.Now(),
+		Payload: ev,
+	}
+	if len(opt) > 0 {
+		msg.Extra = opt
+	}
+
+	buf, err := json.Marshal(&msg)
+	if err != nil {
+		return
+	}
+
+	s.mut.RLock()
+	for c := range s.clients {
+		if !c.subscribed(msg.Type) {
+			continue
+		}
+		select {
+		case c.queue <- buf:
+		default:
+			// Client is too slow to keep up, drop the message rather than block the firing goroutine
+		}
+	}
+	s.mut.RUnlock()
+}
+
+func (s *Server) addClient(c *client) {
+	s.mut.Lock()
+	s.clients[c] = struct{}{}
+	s.mut.Unlock()
+}
+
+func (s *Server) removeClient(c *client) {
+	s.mut.Lock()
+	_, ok := s.clients[c]
+	delete(s.clients, c)
+	s.mut.Unlock()
+
+	if ok {
+		close(c.closed)
+	}
+}
+
+// ServeHTTP dispatches to ServeWS for WebSocket upgrade requests and ServeSSE otherwise
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if websocket.IsWebSocketUpgrade(r) {
+		s.ServeWS(w, r)
+	} else {
+		s.ServeSSE(w, r)
+	}
+}
+
+// ServeSSE streams Events to r as text/event-stream until the client disconnects.
+// The "type" query parameter may be repeated to subscribe to a subset of event types.
+func (s *Server) ServeSSE(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	var c = newClient(r.URL.Query()["type"])
+	s.addClient(c)
+	defer s.removeClient(c)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	var ctx = r.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case buf := <-c.queue:
+			if _, err := w.Write([]byte("data: ")); err != nil {
+				return
+			}
+			if _, err := w.Write(buf); err != nil {
+				return
+			}
+			if _, err := w.Write([]byte("\n\n")); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// ServeWS upgrades r to a WebSocket connection and streams Events as JSON text frames until the
+// client disconnects. The "type" query parameter may be repeated to subscribe to a subset of
+// event types.
+func (s *Server) ServeWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	var c = newClient(r.URL.Query()["type"])
+	s.addClient(c)
+	defer s.removeClient(c)
+
+	// Discard anything the client sends; we only ever write
+	go func() {
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				s.removeClient(c)
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-c.closed:
+			return
+		case buf := <-c.queue:
+			if err := conn.WriteMessage(websocket.TextMessage, buf); err != nil {
+				return
+			}
+		}
+	}
+}
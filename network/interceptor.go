@@ -0,0 +1,100 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network
+
+import (
+	"errors"
+)
+
+// ErrInterceptorPacketType is returned when a PacketInterceptor hands next() a packet whose
+// type is incompatible with the Conn that is invoking it
+var ErrInterceptorPacketType = errors.New("network: interceptor returned packet of unexpected type")
+
+// PacketInterceptor observes (and optionally rewrites) every packet sent or received on a
+// W3GSConn, W3GSPacketConn, BNCSConn, or CAPIConn. Implementations must call next to continue
+// the chain; the final next in the chain performs the actual serialization or hands the packet
+// back to the caller. Returning an error without calling next aborts the Send, NextPacket, or
+// Run call in progress with that error. Returning nil without calling next drops a packet being
+// sent (Send then reports 0 bytes and no error); a received packet is not dropped this way, as
+// NextPacket then returns the packet as it was deserialized.
+type PacketInterceptor interface {
+	// OnSend is invoked before pkt is serialized and written to the underlying connection
+	OnSend(pkt interface{}, next func(interface{}) error) error
+
+	// OnReceive is invoked after pkt has been deserialized from the underlying connection
+	OnReceive(pkt interface{}, next func(interface{}) error) error
+}
+
+// interceptorChain maintains an ordered list of PacketInterceptor and threads packets through
+// them in registration order. The zero value is an empty, ready-to-use chain.
+type interceptorChain struct {
+	mut RWMutex
+	ice []PacketInterceptor
+}
+
+// Add appends i to the chain
+func (c *interceptorChain) Add(i PacketInterceptor) {
+	c.mut.Lock()
+	c.ice = append(c.ice, i)
+	c.mut.Unlock()
+}
+
+// Remove removes the first occurrence of i from the chain, if present
+func (c *interceptorChain) Remove(i PacketInterceptor) {
+	c.mut.Lock()
+	for idx, e := range c.ice {
+		if e == i {
+			// Copy into a new slice so snapshots taken earlier keep their contents
+			var ice = make([]PacketInterceptor, 0, len(c.ice)-1)
+			ice = append(ice, c.ice[:idx]...)
+			c.ice = append(ice, c.ice[idx+1:]...)
+			break
+		}
+	}
+	c.mut.Unlock()
+}
+
+func (c *interceptorChain) snapshot() []PacketInterceptor {
+	c.mut.RLock()
+	var ice = c.ice
+	c.mut.RUnlock()
+	return ice
+}
+
+// Send threads pkt through the chain's OnSend hooks before invoking final
+func (c *interceptorChain) Send(pkt interface{}, final func(interface{}) error) error {
+	var ice = c.snapshot()
+	if len(ice) == 0 {
+		return final(pkt)
+	}
+
+	var call func(i int, pkt interface{}) error
+	call = func(i int, pkt interface{}) error {
+		if i >= len(ice) {
+			return final(pkt)
+		}
+		return ice[i].OnSend(pkt, func(p interface{}) error { return call(i+1, p) })
+	}
+
+	return call(0, pkt)
+}
+
+// Receive threads pkt through the chain's OnReceive hooks before invoking final
+func (c *interceptorChain) Receive(pkt interface{}, final func(interface{}) error) error {
+	var ice = c.snapshot()
+	if len(ice) == 0 {
+		return final(pkt)
+	}
+
+	var call func(i int, pkt interface{}) error
+	call = func(i int, pkt interface{}) error {
+		if i >= len(ice) {
+			return final(pkt)
+		}
+		return ice[i].OnReceive(pkt, func(p interface{}) error { return call(i+1, p) })
+	}
+
+	return call(0, pkt)
+}
@@ -0,0 +1,117 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network
+
+import (
+	"errors"
+	"testing"
+)
+
+type recordingInterceptor struct {
+	name string
+	log  *[]string
+}
+
+func (i *recordingInterceptor) OnSend(pkt interface{}, next func(interface{}) error) error {
+	*i.log = append(*i.log, i.name)
+	return next(pkt)
+}
+
+func (i *recordingInterceptor) OnReceive(pkt interface{}, next func(interface{}) error) error {
+	*i.log = append(*i.log, i.name)
+	return next(pkt)
+}
+
+func TestInterceptorChainOrder(t *testing.T) {
+	var log []string
+	var c interceptorChain
+	c.Add(&recordingInterceptor{name: "first", log: &log})
+	c.Add(&recordingInterceptor{name: "second", log: &log})
+	c.Add(&recordingInterceptor{name: "third", log: &log})
+
+	var finalCalled bool
+	if err := c.Send("pkt", func(interface{}) error { finalCalled = true; return nil }); err != nil {
+		t.Fatalf("Send() = %v, want nil", err)
+	}
+
+	if !finalCalled {
+		t.Fatal("final was never invoked")
+	}
+
+	var want = []string{"first", "second", "third"}
+	if len(log) != len(want) {
+		t.Fatalf("log = %v, want %v", log, want)
+	}
+	for i := range want {
+		if log[i] != want[i] {
+			t.Fatalf("log = %v, want %v", log, want)
+		}
+	}
+}
+
+func TestInterceptorChainEmpty(t *testing.T) {
+	var c interceptorChain
+
+	var finalCalled bool
+	if err := c.Receive("pkt", func(interface{}) error { finalCalled = true; return nil }); err != nil {
+		t.Fatalf("Receive() = %v, want nil", err)
+	}
+	if !finalCalled {
+		t.Fatal("final was never invoked for an empty chain")
+	}
+}
+
+type abortingInterceptor struct {
+	err error
+}
+
+func (i *abortingInterceptor) OnSend(pkt interface{}, next func(interface{}) error) error {
+	return i.err
+}
+
+func (i *abortingInterceptor) OnReceive(pkt interface{}, next func(interface{}) error) error {
+	return i.err
+}
+
+func TestInterceptorChainAbort(t *testing.T) {
+	var errAbort = errors.New("abort")
+
+	var log []string
+	var c interceptorChain
+	c.Add(&abortingInterceptor{err: errAbort})
+	c.Add(&recordingInterceptor{name: "unreached", log: &log})
+
+	var finalCalled bool
+	var err = c.Send("pkt", func(interface{}) error { finalCalled = true; return nil })
+
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("Send() = %v, want %v", err, errAbort)
+	}
+	if finalCalled {
+		t.Fatal("final was invoked despite an interceptor aborting the chain")
+	}
+	if len(log) != 0 {
+		t.Fatalf("log = %v, want empty (chain must stop at the aborting interceptor)", log)
+	}
+}
+
+func TestInterceptorChainRemove(t *testing.T) {
+	var log []string
+	var c interceptorChain
+	var first = &recordingInterceptor{name: "first", log: &log}
+	var second = &recordingInterceptor{name: "second", log: &log}
+	c.Add(first)
+	c.Add(second)
+	c.Remove(first)
+
+	if err := c.Send("pkt", func(interface{}) error { return nil }); err != nil {
+		t.Fatalf("Send() = %v, want nil", err)
+	}
+
+	var want = []string{"second"}
+	if len(log) != len(want) || log[0] != want[0] {
+		t.Fatalf("log = %v, want %v", log, want)
+	}
+}
@@ -7,9 +7,9 @@ package network
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"io/ioutil"
-	"math"
 	"net"
 	"sync"
 	"time"
@@ -41,6 +41,8 @@ type W3GSPacketConn struct {
 
 	bbuf [2048]byte
 	rbuf w3gs.DeserializationBuffer
+
+	ices interceptorChain
 }
 
 // NewW3GSPacketConn returns conn wrapped in W3GSPacketConn
@@ -64,6 +66,17 @@ func (c *W3GSPacketConn) SetConn(conn net.PacketConn) {
 	c.cmut.Unlock()
 }
 
+// AddInterceptor registers i to observe (and optionally rewrite, or drop when sending) every
+// packet sent or received on c
+func (c *W3GSPacketConn) AddInterceptor(i PacketInterceptor) {
+	c.ices.Add(i)
+}
+
+// RemoveInterceptor unregisters a previously added interceptor
+func (c *W3GSPacketConn) RemoveInterceptor(i PacketInterceptor) {
+	c.ices.Remove(i)
+}
+
 // Close closes the connection
 func (c *W3GSPacketConn) Close() error {
 	c.cmut.RLock()
@@ -80,25 +93,33 @@ func (c *W3GSPacketConn) Close() error {
 
 // Send pkt to addr over net.PacketConn
 func (c *W3GSPacketConn) Send(addr net.Addr, pkt w3gs.Packet) (int, error) {
-	c.cmut.RLock()
+	var n int
+	var err = c.ices.Send(pkt, func(p interface{}) error {
+		wp, ok := p.(w3gs.Packet)
+		if !ok {
+			return ErrInterceptorPacketType
+		}
 
-	if c.conn == nil {
-		c.cmut.RUnlock()
-		return 0, io.EOF
-	}
+		c.cmut.RLock()
 
-	var n int
-	var e error
+		if c.conn == nil {
+			c.cmut.RUnlock()
+			return ErrConnClosed
+		}
 
-	c.smut.Lock()
-	c.sbuf.Truncate()
-	if e = pkt.Serialize(&c.sbuf); e == nil {
-		n, e = c.conn.WriteTo(c.sbuf.Bytes, addr)
-	}
-	c.smut.Unlock()
-	c.cmut.RUnlock()
+		var e error
+		c.smut.Lock()
+		c.sbuf.Truncate()
+		if e = wp.Serialize(&c.sbuf); e == nil {
+			n, e = c.conn.WriteTo(c.sbuf.Bytes, addr)
+		}
+		c.smut.Unlock()
+		c.cmut.RUnlock()
 
-	return n, e
+		return e
+	})
+
+	return n, wrapConnErr("Send", addr, err)
 }
 
 // Broadcast a packet over LAN
@@ -113,30 +134,39 @@ func (c *W3GSPacketConn) NextPacket(timeout time.Duration) (w3gs.Packet, net.Add
 
 	if c.conn == nil {
 		c.cmut.RUnlock()
-		return nil, nil, io.EOF
+		return nil, nil, wrapConnErr("NextPacket", nil, ErrConnClosed)
 	}
 
 	if timeout != 0 {
 		if err := c.conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
 			c.cmut.RUnlock()
-			return nil, nil, err
+			return nil, nil, wrapConnErr("NextPacket", nil, err)
 		}
 	}
 
 	size, addr, err := c.conn.ReadFrom(c.bbuf[:])
 	if err != nil {
 		c.cmut.RUnlock()
-		return nil, nil, err
+		return nil, nil, wrapConnErr("NextPacket", addr, err)
 	}
 
 	pkt, _, err := w3gs.DeserializePacketWithBuffer(&protocol.Buffer{Bytes: c.bbuf[:size]}, &c.rbuf)
 	c.cmut.RUnlock()
 
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, wrapConnErr("NextPacket", addr, err)
 	}
 
-	return pkt, addr, err
+	err = c.ices.Receive(pkt, func(p interface{}) error {
+		wp, ok := p.(w3gs.Packet)
+		if !ok {
+			return ErrInterceptorPacketType
+		}
+		pkt = wp
+		return nil
+	})
+
+	return pkt, addr, wrapConnErr("NextPacket", addr, err)
 }
 
 // Run reads packets (with given max time between packets) from Conn and emits an event for each received packet
@@ -148,9 +178,12 @@ func (c *W3GSPacketConn) Run(f Emitter, timeout time.Duration) error {
 		pkt, addr, err := c.NextPacket(timeout)
 
 		if err != nil {
-			switch err {
+			switch {
 			// Connection is still valid after these errors, only deserialization failed
-			case w3gs.ErrInvalidPacketSize, w3gs.ErrInvalidChecksum, w3gs.ErrUnexpectedConst, w3gs.ErrBufferTooSmall:
+			case errors.Is(err, w3gs.ErrInvalidPacketSize),
+				errors.Is(err, w3gs.ErrInvalidChecksum),
+				errors.Is(err, w3gs.ErrUnexpectedConst),
+				errors.Is(err, w3gs.ErrBufferTooSmall):
 				f.Fire(&AsyncError{Src: "Run[NextPacket]", Err: err})
 				continue
 			default:
@@ -173,6 +206,8 @@ type W3GSConn struct {
 	smut sync.Mutex
 	sbuf w3gs.SerializationBuffer
 	rbuf w3gs.DeserializationBuffer
+
+	ices interceptorChain
 }
 
 // NewW3GSConn returns conn wrapped in W3GSConn
@@ -196,6 +231,17 @@ func (c *W3GSConn) SetConn(conn net.Conn) {
 	c.cmut.Unlock()
 }
 
+// AddInterceptor registers i to observe (and optionally rewrite, or drop when sending) every
+// packet sent or received on c
+func (c *W3GSConn) AddInterceptor(i PacketInterceptor) {
+	c.ices.Add(i)
+}
+
+// RemoveInterceptor unregisters a previously added interceptor
+func (c *W3GSConn) RemoveInterceptor(i PacketInterceptor) {
+	c.ices.Remove(i)
+}
+
 // Close closes the connection
 func (c *W3GSConn) Close() error {
 	c.cmut.RLock()
@@ -212,19 +258,33 @@ func (c *W3GSConn) Close() error {
 
 // Send pkt to addr over net.Conn
 func (c *W3GSConn) Send(pkt w3gs.Packet) (int, error) {
-	c.cmut.RLock()
+	var n int
+	var addr net.Addr
+	var err = c.ices.Send(pkt, func(p interface{}) error {
+		wp, ok := p.(w3gs.Packet)
+		if !ok {
+			return ErrInterceptorPacketType
+		}
 
-	if c.conn == nil {
+		c.cmut.RLock()
+
+		if c.conn == nil {
+			c.cmut.RUnlock()
+			return ErrConnClosed
+		}
+
+		addr = c.conn.RemoteAddr()
+
+		var e error
+		c.smut.Lock()
+		n, e = w3gs.SerializePacketWithBuffer(c.conn, &c.sbuf, wp)
+		c.smut.Unlock()
 		c.cmut.RUnlock()
-		return 0, io.EOF
-	}
 
-	c.smut.Lock()
-	var n, err = w3gs.SerializePacketWithBuffer(c.conn, &c.sbuf, pkt)
-	c.smut.Unlock()
-	c.cmut.RUnlock()
+		return e
+	})
 
-	return n, err
+	return n, wrapConnErr("Send", addr, err)
 }
 
 // NextPacket waits for the next packet (with given timeout) and returns its deserialized representation
@@ -234,20 +294,35 @@ func (c *W3GSConn) NextPacket(timeout time.Duration) (w3gs.Packet, error) {
 
 	if c.conn == nil {
 		c.cmut.RUnlock()
-		return nil, io.EOF
+		return nil, wrapConnErr("NextPacket", nil, ErrConnClosed)
 	}
 
+	var addr = c.conn.RemoteAddr()
+
 	if timeout != 0 {
 		if err := c.conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
 			c.cmut.RUnlock()
-			return nil, err
+			return nil, wrapConnErr("NextPacket", addr, err)
 		}
 	}
 
 	pkt, _, err := w3gs.DeserializePacketWithBuffer(c.conn, &c.rbuf)
 	c.cmut.RUnlock()
 
-	return pkt, err
+	if err != nil {
+		return nil, wrapConnErr("NextPacket", addr, err)
+	}
+
+	err = c.ices.Receive(pkt, func(p interface{}) error {
+		wp, ok := p.(w3gs.Packet)
+		if !ok {
+			return ErrInterceptorPacketType
+		}
+		pkt = wp
+		return nil
+	})
+
+	return pkt, wrapConnErr("NextPacket", addr, err)
 }
 
 // Run reads packets (with given max time between packets) from Conn and fires an event through f for each received packet
@@ -259,8 +334,11 @@ func (c *W3GSConn) Run(f Emitter, timeout time.Duration) error {
 		pkt, err := c.NextPacket(timeout)
 
 		if err != nil {
-			switch err {
-			case w3gs.ErrInvalidPacketSize, w3gs.ErrInvalidChecksum, w3gs.ErrUnexpectedConst, w3gs.ErrBufferTooSmall:
+			switch {
+			case errors.Is(err, w3gs.ErrInvalidPacketSize),
+				errors.Is(err, w3gs.ErrInvalidChecksum),
+				errors.Is(err, w3gs.ErrUnexpectedConst),
+				errors.Is(err, w3gs.ErrBufferTooSmall):
 				// Connection is still valid after these errors, only deserialization failed
 				f.Fire(&AsyncError{Src: "Run[NextPacket]", Err: err})
 				continue
@@ -287,11 +365,14 @@ type BNCSConn struct {
 
 	lmut sync.Mutex
 	lnxt time.Time
+	rl   RateLimiter
+
+	ices interceptorChain
 }
 
 // NewBNCSConn returns conn wrapped in BNCSConn
 func NewBNCSConn(conn net.Conn) *BNCSConn {
-	return &BNCSConn{conn: conn}
+	return &BNCSConn{conn: conn, rl: NewBattlenetFloodLimiter()}
 }
 
 // Conn returns the underlying net.Conn
@@ -310,6 +391,25 @@ func (c *BNCSConn) SetConn(conn net.Conn) {
 	c.cmut.Unlock()
 }
 
+// AddInterceptor registers i to observe (and optionally rewrite, or drop when sending) every
+// packet sent or received on c
+func (c *BNCSConn) AddInterceptor(i PacketInterceptor) {
+	c.ices.Add(i)
+}
+
+// RemoveInterceptor unregisters a previously added interceptor
+func (c *BNCSConn) RemoveInterceptor(i PacketInterceptor) {
+	c.ices.Remove(i)
+}
+
+// SetRateLimiter replaces the RateLimiter used by SendRL/TrySendRL/Reserve. Pass nil to disable
+// rate limiting.
+func (c *BNCSConn) SetRateLimiter(rl RateLimiter) {
+	c.lmut.Lock()
+	c.rl = rl
+	c.lmut.Unlock()
+}
+
 // Close closes the connection
 func (c *BNCSConn) Close() error {
 	c.cmut.RLock()
@@ -326,65 +426,121 @@ func (c *BNCSConn) Close() error {
 
 // Send pkt to addr over net.Conn
 func (c *BNCSConn) Send(pkt bncs.Packet) (int, error) {
-	c.cmut.RLock()
+	var n int
+	var addr net.Addr
+	var err = c.ices.Send(pkt, func(p interface{}) error {
+		wp, ok := p.(bncs.Packet)
+		if !ok {
+			return ErrInterceptorPacketType
+		}
 
-	if c.conn == nil {
+		c.cmut.RLock()
+
+		if c.conn == nil {
+			c.cmut.RUnlock()
+			return ErrConnClosed
+		}
+
+		addr = c.conn.RemoteAddr()
+
+		var e error
+		c.smut.Lock()
+		n, e = bncs.SerializePacketWithBuffer(c.conn, &c.sbuf, wp)
+		c.smut.Unlock()
 		c.cmut.RUnlock()
-		return 0, io.EOF
-	}
 
-	c.smut.Lock()
-	var n, err = bncs.SerializePacketWithBuffer(c.conn, &c.sbuf, pkt)
-	c.smut.Unlock()
-	c.cmut.RUnlock()
+		return e
+	})
 
-	return n, err
+	return n, wrapConnErr("Send", addr, err)
 }
 
-// SendRL pkt to addr over net.Conn with rate limit
-func (c *BNCSConn) SendRL(pkt bncs.Packet) (int, error) {
+func (c *BNCSConn) sendRL(pkt bncs.Packet, block bool) (int, error) {
 	c.lmut.Lock()
+	defer c.lmut.Unlock()
 
-	var t = time.Now()
-	if t.Before(c.lnxt) {
-		time.Sleep(c.lnxt.Sub(t))
+	var now = time.Now()
+	if now.Before(c.lnxt) {
+		if !block {
+			return 0, ErrRateLimited
+		}
+		time.Sleep(c.lnxt.Sub(now))
 	}
 
-	var n, err = c.Send(pkt)
+	n, err := c.Send(pkt)
+
 	if n > 0 {
-		// log(packet_size,4)^1.5 × 1300ms
-		// ~1.3s for packet size 4
-		// ~2.8s for packet size 10
-		// ~4.6s for packet size 25
-		// ~6.2s for packet size 50
-		// ~9.7s for packet size 200
-		c.lnxt = time.Now().Add(time.Duration(math.Pow(math.Log(float64(n))/math.Log(4), 1.5)) * (1300 * time.Millisecond))
+		if rl := c.rl; rl != nil {
+			c.lnxt = time.Now().Add(rl.Reserve(n))
+		}
 	}
-	c.lmut.Unlock()
 
 	return n, err
 }
 
+// SendRL sends pkt like Send, blocking until the configured RateLimiter admits it
+func (c *BNCSConn) SendRL(pkt bncs.Packet) (int, error) {
+	return c.sendRL(pkt, true)
+}
+
+// TrySendRL sends pkt like SendRL, but returns ErrRateLimited instead of blocking if the
+// configured RateLimiter has not yet admitted the next send
+func (c *BNCSConn) TrySendRL(pkt bncs.Packet) (int, error) {
+	return c.sendRL(pkt, false)
+}
+
+// Reserve reports the delay a hypothetical packet of size bytes would currently incur against
+// the configured RateLimiter, without consuming any of its budget. This is independent of
+// SendRL/TrySendRL's own internal gate (which consumes budget on an actual send), letting
+// callers plan ahead for a send whose size is known before serialization without starving the
+// budget for the send that follows, or for other BNCSConns sharing the same RateLimiter.
+func (c *BNCSConn) Reserve(size int) time.Duration {
+	c.lmut.Lock()
+	var rl = c.rl
+	c.lmut.Unlock()
+
+	if rl == nil {
+		return 0
+	}
+
+	return rl.Peek(size)
+}
+
 // NextClientPacket waits for the next client packet (with given timeout) and returns its deserialized representation
 // Not safe for concurrent invocation
 func (c *BNCSConn) NextClientPacket(timeout time.Duration) (bncs.Packet, error) {
 	c.cmut.RLock()
 	if c.conn == nil {
 		c.cmut.RUnlock()
-		return nil, io.EOF
+		return nil, wrapConnErr("NextClientPacket", nil, ErrConnClosed)
 	}
 
+	var addr = c.conn.RemoteAddr()
+
 	if timeout != 0 {
 		if err := c.conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
 			c.cmut.RUnlock()
-			return nil, err
+			return nil, wrapConnErr("NextClientPacket", addr, err)
 		}
 	}
 
 	pkt, _, err := bncs.DeserializeClientPacketWithBuffer(c.conn, &c.rbuf)
 	c.cmut.RUnlock()
 
-	return pkt, err
+	if err != nil {
+		return nil, wrapConnErr("NextClientPacket", addr, err)
+	}
+
+	err = c.ices.Receive(pkt, func(p interface{}) error {
+		wp, ok := p.(bncs.Packet)
+		if !ok {
+			return ErrInterceptorPacketType
+		}
+		pkt = wp
+		return nil
+	})
+
+	return pkt, wrapConnErr("NextClientPacket", addr, err)
 }
 
 // NextServerPacket waits for the next server packet (with given timeout) and returns its deserialized representation
@@ -394,20 +550,35 @@ func (c *BNCSConn) NextServerPacket(timeout time.Duration) (bncs.Packet, error)
 
 	if c.conn == nil {
 		c.cmut.RUnlock()
-		return nil, io.EOF
+		return nil, wrapConnErr("NextServerPacket", nil, ErrConnClosed)
 	}
 
+	var addr = c.conn.RemoteAddr()
+
 	if timeout != 0 {
 		if err := c.conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
 			c.cmut.RUnlock()
-			return nil, err
+			return nil, wrapConnErr("NextServerPacket", addr, err)
 		}
 	}
 
 	pkt, _, err := bncs.DeserializeServerPacketWithBuffer(c.conn, &c.rbuf)
 	c.cmut.RUnlock()
 
-	return pkt, err
+	if err != nil {
+		return nil, wrapConnErr("NextServerPacket", addr, err)
+	}
+
+	err = c.ices.Receive(pkt, func(p interface{}) error {
+		wp, ok := p.(bncs.Packet)
+		if !ok {
+			return ErrInterceptorPacketType
+		}
+		pkt = wp
+		return nil
+	})
+
+	return pkt, wrapConnErr("NextServerPacket", addr, err)
 }
 
 // RunServer reads client packets (with given max time between packets) from Conn and emits an event for each received packet
@@ -419,9 +590,12 @@ func (c *BNCSConn) RunServer(f Emitter, timeout time.Duration) error {
 		pkt, err := c.NextClientPacket(timeout)
 
 		if err != nil {
-			switch err {
+			switch {
 			// Connection is still valid after these errors, only deserialization failed
-			case bncs.ErrInvalidPacketSize, bncs.ErrInvalidChecksum, bncs.ErrUnexpectedConst, bncs.ErrBufferTooSmall:
+			case errors.Is(err, bncs.ErrInvalidPacketSize),
+				errors.Is(err, bncs.ErrInvalidChecksum),
+				errors.Is(err, bncs.ErrUnexpectedConst),
+				errors.Is(err, bncs.ErrBufferTooSmall):
 				f.Fire(&AsyncError{Src: "RunServer[NextPacket]", Err: err})
 				continue
 			default:
@@ -444,9 +618,12 @@ func (c *BNCSConn) RunClient(f Emitter, timeout time.Duration) error {
 		pkt, err := c.NextServerPacket(timeout)
 
 		if err != nil {
-			switch err {
+			switch {
 			// Connection is still valid after these errors, only deserialization failed
-			case bncs.ErrInvalidPacketSize, bncs.ErrInvalidChecksum, bncs.ErrUnexpectedConst, bncs.ErrBufferTooSmall:
+			case errors.Is(err, bncs.ErrInvalidPacketSize),
+				errors.Is(err, bncs.ErrInvalidChecksum),
+				errors.Is(err, bncs.ErrUnexpectedConst),
+				errors.Is(err, bncs.ErrBufferTooSmall):
 				f.Fire(&AsyncError{Src: "RunClient[NextPacket]", Err: err})
 				continue
 			default:
@@ -475,6 +652,8 @@ type CAPIConn struct {
 
 	cmut RWMutex
 	smut sync.Mutex
+
+	ices interceptorChain
 }
 
 // NewCAPIConn returns conn wrapped in CAPIConn
@@ -498,6 +677,17 @@ func (c *CAPIConn) SetConn(conn *websocket.Conn) {
 	c.cmut.Unlock()
 }
 
+// AddInterceptor registers i to observe (and optionally rewrite, or drop when sending) every
+// packet sent or received on c
+func (c *CAPIConn) AddInterceptor(i PacketInterceptor) {
+	c.ices.Add(i)
+}
+
+// RemoveInterceptor unregisters a previously added interceptor
+func (c *CAPIConn) RemoveInterceptor(i PacketInterceptor) {
+	c.ices.Remove(i)
+}
+
 // Close closes the connection
 func (c *CAPIConn) Close() error {
 	c.cmut.RLock()
@@ -514,25 +704,37 @@ func (c *CAPIConn) Close() error {
 
 // Send pkt to addr over net.Conn
 func (c *CAPIConn) Send(pkt *capi.Packet) error {
-	c.cmut.RLock()
+	var addr net.Addr
+	var err = c.ices.Send(pkt, func(p interface{}) error {
+		wp, ok := p.(*capi.Packet)
+		if !ok {
+			return ErrInterceptorPacketType
+		}
 
-	if c.conn == nil {
-		c.cmut.RUnlock()
-		return io.EOF
-	}
+		c.cmut.RLock()
+
+		if c.conn == nil {
+			c.cmut.RUnlock()
+			return ErrConnClosed
+		}
 
-	c.smut.Lock()
+		addr = c.conn.RemoteAddr()
 
-	w, err := c.conn.NextWriter(websocket.TextMessage)
-	if err == nil {
-		err = capi.SerializePacket(w, pkt)
-		w.Close()
-	}
+		c.smut.Lock()
 
-	c.smut.Unlock()
-	c.cmut.RUnlock()
+		w, err := c.conn.NextWriter(websocket.TextMessage)
+		if err == nil {
+			err = capi.SerializePacket(w, wp)
+			w.Close()
+		}
 
-	return err
+		c.smut.Unlock()
+		c.cmut.RUnlock()
+
+		return err
+	})
+
+	return wrapConnErr("Send", addr, err)
 }
 
 // NextPacket waits for the next packet (with given timeout) and returns its deserialized representation
@@ -542,13 +744,15 @@ func (c *CAPIConn) NextPacket(timeout time.Duration) (*capi.Packet, error) {
 
 	if c.conn == nil {
 		c.cmut.RUnlock()
-		return nil, io.EOF
+		return nil, wrapConnErr("NextPacket", nil, ErrConnClosed)
 	}
 
+	var addr = c.conn.RemoteAddr()
+
 	if timeout != 0 {
 		if err := c.conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
 			c.cmut.RUnlock()
-			return nil, err
+			return nil, wrapConnErr("NextPacket", addr, err)
 		}
 	}
 
@@ -562,7 +766,20 @@ func (c *CAPIConn) NextPacket(timeout time.Duration) (*capi.Packet, error) {
 
 	c.cmut.RUnlock()
 
-	return pkt, err
+	if err != nil {
+		return nil, wrapConnErr("NextPacket", addr, err)
+	}
+
+	err = c.ices.Receive(pkt, func(p interface{}) error {
+		wp, ok := p.(*capi.Packet)
+		if !ok {
+			return ErrInterceptorPacketType
+		}
+		pkt = wp
+		return nil
+	})
+
+	return pkt, wrapConnErr("NextPacket", addr, err)
 }
 
 // Run reads packets (with given max time between packets) from Conn and fires an event through f for each received packet
@@ -574,9 +791,11 @@ func (c *CAPIConn) Run(f Emitter, timeout time.Duration) error {
 		pkt, err := c.NextPacket(timeout)
 
 		if err != nil {
-			switch err.(type) {
+			var serr *json.SyntaxError
+			var terr *json.UnmarshalTypeError
+			switch {
 			// Connection is still valid after these errors, only deserialization failed
-			case *json.SyntaxError, *json.UnmarshalTypeError:
+			case errors.As(err, &serr), errors.As(err, &terr):
 				f.Fire(&AsyncError{Src: "Run[NextPacket]", Err: err})
 				continue
 			default:
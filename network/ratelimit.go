@@ -0,0 +1,110 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned by TrySendRL when the configured RateLimiter has not yet admitted
+// the next send
+var ErrRateLimited = errors.New("network: rate limited")
+
+// RateLimiter decides how long a caller must wait before a packet of a given size is admitted
+type RateLimiter interface {
+	// Reserve consumes budget for a packet of size bytes and returns how long the caller must
+	// wait before it may be sent
+	Reserve(size int) time.Duration
+
+	// Peek reports how long a packet of size bytes would currently have to wait, without
+	// consuming any budget, so callers can plan ahead without affecting a later Reserve
+	Peek(size int) time.Duration
+}
+
+// TokenBucket is a RateLimiter that grants a packet of size bytes against a bucket of tokens
+// refilled at a fixed rate, at a cost determined by a cost function. The zero value is not
+// usable; construct with NewTokenBucket.
+type TokenBucket struct {
+	mut      sync.Mutex
+	capacity float64
+	tokens   float64
+	rate     float64 // tokens per second
+	cost     func(size int) float64
+	last     time.Time
+}
+
+// NewTokenBucket returns a TokenBucket with the given burst capacity and refill rate (tokens per
+// second). cost determines how many tokens a packet of a given size consumes.
+func NewTokenBucket(capacity float64, rate float64, cost func(size int) float64) *TokenBucket {
+	return &TokenBucket{
+		capacity: capacity,
+		tokens:   capacity,
+		rate:     rate,
+		cost:     cost,
+		last:     time.Now(),
+	}
+}
+
+// refill returns the token count as of now, without storing it
+func (b *TokenBucket) refill(now time.Time) float64 {
+	var tokens = b.tokens
+	if b.rate > 0 {
+		tokens += now.Sub(b.last).Seconds() * b.rate
+		if tokens > b.capacity {
+			tokens = b.capacity
+		}
+	}
+	return tokens
+}
+
+func (b *TokenBucket) delay(tokens float64) time.Duration {
+	if tokens >= 0 || b.rate <= 0 {
+		return 0
+	}
+	return time.Duration(-tokens / b.rate * float64(time.Second))
+}
+
+// Reserve implements RateLimiter
+func (b *TokenBucket) Reserve(size int) time.Duration {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	var now = time.Now()
+	b.tokens = b.refill(now)
+	b.last = now
+
+	b.tokens -= b.cost(size)
+	return b.delay(b.tokens)
+}
+
+// Peek implements RateLimiter
+func (b *TokenBucket) Peek(size int) time.Duration {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	return b.delay(b.refill(time.Now()) - b.cost(size))
+}
+
+// BattlenetFloodCost is the cost function Battle.net's own flood-control throttling has
+// historically been observed to follow:
+//
+//	log(packet_size,4)^1.5 × 1300ms
+//	~1.3s for packet size 4
+//	~2.8s for packet size 10
+//	~4.6s for packet size 25
+//	~6.2s for packet size 50
+//	~9.7s for packet size 200
+func BattlenetFloodCost(size int) float64 {
+	return math.Pow(math.Log(float64(size))/math.Log(4), 1.5) * 1300
+}
+
+// NewBattlenetFloodLimiter returns a TokenBucket enforcing BattlenetFloodCost with no burst
+// capacity, reproducing BNCSConn's original fixed anti-flood curve
+func NewBattlenetFloodLimiter() *TokenBucket {
+	return NewTokenBucket(0, 1000, BattlenetFloodCost)
+}
@@ -0,0 +1,63 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenBucketReserve(t *testing.T) {
+	var b = NewTokenBucket(10, 10, func(size int) float64 { return float64(size) })
+
+	if d := b.Reserve(5); d != 0 {
+		t.Fatalf("Reserve(5) = %v, want 0 (within capacity)", d)
+	}
+	if d := b.Reserve(5); d != 0 {
+		t.Fatalf("second Reserve(5) = %v, want 0 (bucket exactly drained)", d)
+	}
+
+	// Bucket is now empty; one more token's worth of cost must wait ~1/rate seconds
+	var d = b.Reserve(1)
+	var want = time.Second / 10
+	if d < want-time.Millisecond || d > want+10*time.Millisecond {
+		t.Fatalf("Reserve(1) on empty bucket = %v, want ~%v", d, want)
+	}
+}
+
+func TestTokenBucketReserveRefills(t *testing.T) {
+	var b = NewTokenBucket(10, 10, func(size int) float64 { return float64(size) })
+
+	b.Reserve(10)
+	b.last = b.last.Add(-time.Second) // pretend a full second has already elapsed
+
+	if d := b.Reserve(10); d != 0 {
+		t.Fatalf("Reserve(10) after a full refill = %v, want 0", d)
+	}
+}
+
+func TestTokenBucketPeekDoesNotConsume(t *testing.T) {
+	var b = NewTokenBucket(10, 10, func(size int) float64 { return float64(size) })
+
+	if d := b.Peek(10); d != 0 {
+		t.Fatalf("Peek(10) = %v, want 0", d)
+	}
+	if d := b.Peek(10); d != 0 {
+		t.Fatalf("repeated Peek(10) = %v, want 0 (Peek must not consume budget)", d)
+	}
+
+	// The budget Peek reported as available must still be there for an actual Reserve
+	if d := b.Reserve(10); d != 0 {
+		t.Fatalf("Reserve(10) after Peek(10) = %v, want 0", d)
+	}
+}
+
+func TestTokenBucketUnlimitedRate(t *testing.T) {
+	var b = NewTokenBucket(0, 0, func(size int) float64 { return float64(size) })
+
+	if d := b.Reserve(1 << 20); d != 0 {
+		t.Fatalf("Reserve on a zero-rate bucket = %v, want 0 (never throttles)", d)
+	}
+}
@@ -0,0 +1,435 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ErrSelfConnect is returned by a reconnecting Conn's Dialer result when LocalAddr and
+// RemoteAddr are identical, which would otherwise spin the reconnect loop into a self-connect
+var ErrSelfConnect = errors.New("network: refusing to reconnect to self")
+
+// Reconnecting event fires before a reconnecting Conn attempts to re-dial its connection
+type Reconnecting struct {
+	Attempt int
+}
+
+// Reconnected event fires after a reconnecting Conn has successfully re-dialed its connection
+type Reconnected struct {
+	Attempt int
+}
+
+// Backoff configures the exponential backoff (with full jitter) used between reconnect attempts
+type Backoff struct {
+	Min        time.Duration
+	Max        time.Duration
+	Factor     float64
+	MaxRetries int // 0 means retry indefinitely
+}
+
+// DefaultBackoff is used by reconnecting Conns unless overridden
+var DefaultBackoff = Backoff{
+	Min:    500 * time.Millisecond,
+	Max:    30 * time.Second,
+	Factor: 2,
+}
+
+func (b Backoff) delay(attempt int) time.Duration {
+	var min = b.Min
+	if min <= 0 {
+		min = DefaultBackoff.Min
+	}
+	var factor = b.Factor
+	if factor <= 1 {
+		factor = DefaultBackoff.Factor
+	}
+
+	var d = float64(min) * math.Pow(factor, float64(attempt))
+	if max := b.Max; max > 0 && d > float64(max) {
+		d = float64(max)
+	}
+
+	// Full jitter: uniformly distribute in [0, d) so simultaneous reconnects don't thunder the herd
+	return time.Duration(rand.Float64() * d)
+}
+
+// isSelfConnect reports whether local and remote describe the same endpoint, which would
+// indicate a Dialer looped back onto this same connection
+func isSelfConnect(local, remote net.Addr) bool {
+	return local != nil && remote != nil && local.String() == remote.String()
+}
+
+func closeStop(stop chan struct{}) {
+	select {
+	case <-stop:
+	default:
+		close(stop)
+	}
+}
+
+// reconnectLoop invokes run in a loop, calling dial (with Reconnecting/Reconnected events fired
+// through f and exponential backoff between attempts) whenever run returns a recoverable error,
+// until stop is closed, backoff's retry budget is exhausted, or run returns a non-recoverable
+// error
+func reconnectLoop(f Emitter, backoff Backoff, stop chan struct{}, run func() error, dial func() error) error {
+	var attempt int
+	for {
+		var err = run()
+		if err == nil || !(IsConnClosedError(err) || IsTemporaryError(err)) {
+			return err
+		}
+
+		select {
+		case <-stop:
+			return err
+		default:
+		}
+
+		for {
+			select {
+			case <-stop:
+				return err
+			default:
+			}
+
+			if backoff.MaxRetries > 0 && attempt >= backoff.MaxRetries {
+				return err
+			}
+			attempt++
+
+			f.Fire(&Reconnecting{Attempt: attempt})
+
+			select {
+			case <-stop:
+				return err
+			case <-time.After(backoff.delay(attempt - 1)):
+			}
+
+			if derr := dial(); derr != nil {
+				f.Fire(&AsyncError{Src: "Reconnect[Dialer]", Err: derr})
+				continue
+			}
+
+			f.Fire(&Reconnected{Attempt: attempt})
+			attempt = 0
+			break
+		}
+	}
+}
+
+// watchStop closes stop when ctx is done before the returned done function is called, so a
+// reconnect loop blocked on stop reacts to ctx cancellation the same way it reacts to Stop
+func watchStop(ctx context.Context, stop chan struct{}) (done func()) {
+	if ctx.Done() == nil {
+		return func() {}
+	}
+
+	var finished = make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			closeStop(stop)
+		case <-finished:
+		}
+	}()
+
+	return func() { close(finished) }
+}
+
+func dialNetConn(dialer func() (net.Conn, error), setConn func(net.Conn)) error {
+	conn, err := dialer()
+	if err != nil {
+		return err
+	}
+
+	if isSelfConnect(conn.LocalAddr(), conn.RemoteAddr()) {
+		conn.Close()
+		return ErrSelfConnect
+	}
+
+	setConn(conn)
+	return nil
+}
+
+// ReconnectingW3GSConn wraps a W3GSConn and automatically re-dials the underlying net.Conn via
+// Dialer whenever Run fails with a recoverable error (per IsConnClosedError/IsTemporaryError),
+// using exponential backoff. Handlers and interceptors registered on the embedded W3GSConn are
+// preserved across reconnects, since only the underlying net.Conn is swapped via SetConn.
+type ReconnectingW3GSConn struct {
+	*W3GSConn
+
+	// Dialer opens a new underlying net.Conn when a reconnect is needed
+	Dialer func() (net.Conn, error)
+
+	// Backoff configures delay between reconnect attempts
+	Backoff Backoff
+
+	stop chan struct{}
+}
+
+// NewReconnectingW3GSConn returns conn wrapped in a ReconnectingW3GSConn that re-dials using dialer
+func NewReconnectingW3GSConn(conn net.Conn, dialer func() (net.Conn, error)) *ReconnectingW3GSConn {
+	return &ReconnectingW3GSConn{
+		W3GSConn: NewW3GSConn(conn),
+		Dialer:   dialer,
+		Backoff:  DefaultBackoff,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Run reads packets from Conn like W3GSConn.Run, reconnecting via Dialer on recoverable errors
+// until Stop is called or a non-recoverable error occurs
+func (c *ReconnectingW3GSConn) Run(f Emitter, timeout time.Duration) error {
+	return reconnectLoop(f, c.Backoff, c.stop,
+		func() error { return c.W3GSConn.Run(f, timeout) },
+		func() error { return dialNetConn(c.Dialer, c.SetConn) },
+	)
+}
+
+// RunContext runs like Run, reconnecting via Dialer on recoverable errors, but also forces the
+// in-flight attempt to return early and stops reconnecting once ctx is done, returning nil
+// (rather than ctx.Err()) in that case, same as W3GSConn.RunContext
+func (c *ReconnectingW3GSConn) RunContext(ctx context.Context, f Emitter, timeout time.Duration) error {
+	var done = watchStop(ctx, c.stop)
+	defer done()
+
+	var err = reconnectLoop(f, c.Backoff, c.stop,
+		func() error {
+			var conn = c.Conn()
+			if conn == nil {
+				return c.W3GSConn.Run(f, timeout)
+			}
+
+			var stop = watchContext(ctx, func(t time.Time) { conn.SetDeadline(t) })
+			defer stop()
+
+			return c.W3GSConn.Run(f, timeout)
+		},
+		func() error { return dialNetConn(c.Dialer, c.SetConn) },
+	)
+
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// Stop prevents further reconnect attempts and closes the underlying connection
+func (c *ReconnectingW3GSConn) Stop() {
+	closeStop(c.stop)
+	c.Close()
+}
+
+// ReconnectingBNCSConn wraps a BNCSConn and automatically re-dials the underlying net.Conn via
+// Dialer whenever RunServer/RunClient fails with a recoverable error, using exponential backoff.
+// Handlers and interceptors registered on the embedded BNCSConn are preserved across reconnects,
+// since only the underlying net.Conn is swapped via SetConn.
+type ReconnectingBNCSConn struct {
+	*BNCSConn
+
+	// Dialer opens a new underlying net.Conn when a reconnect is needed
+	Dialer func() (net.Conn, error)
+
+	// Backoff configures delay between reconnect attempts
+	Backoff Backoff
+
+	stop chan struct{}
+}
+
+// NewReconnectingBNCSConn returns conn wrapped in a ReconnectingBNCSConn that re-dials using dialer
+func NewReconnectingBNCSConn(conn net.Conn, dialer func() (net.Conn, error)) *ReconnectingBNCSConn {
+	return &ReconnectingBNCSConn{
+		BNCSConn: NewBNCSConn(conn),
+		Dialer:   dialer,
+		Backoff:  DefaultBackoff,
+		stop:     make(chan struct{}),
+	}
+}
+
+// RunServer reads client packets like BNCSConn.RunServer, reconnecting via Dialer on recoverable
+// errors until Stop is called or a non-recoverable error occurs
+func (c *ReconnectingBNCSConn) RunServer(f Emitter, timeout time.Duration) error {
+	return reconnectLoop(f, c.Backoff, c.stop,
+		func() error { return c.BNCSConn.RunServer(f, timeout) },
+		func() error { return dialNetConn(c.Dialer, c.SetConn) },
+	)
+}
+
+// RunClient reads server packets like BNCSConn.RunClient, reconnecting via Dialer on recoverable
+// errors until Stop is called or a non-recoverable error occurs
+func (c *ReconnectingBNCSConn) RunClient(f Emitter, timeout time.Duration) error {
+	return reconnectLoop(f, c.Backoff, c.stop,
+		func() error { return c.BNCSConn.RunClient(f, timeout) },
+		func() error { return dialNetConn(c.Dialer, c.SetConn) },
+	)
+}
+
+// RunServerContext runs like RunServer, reconnecting via Dialer on recoverable errors, but also
+// forces the in-flight attempt to return early and stops reconnecting once ctx is done,
+// returning nil (rather than ctx.Err()) in that case, same as BNCSConn.RunServerContext
+func (c *ReconnectingBNCSConn) RunServerContext(ctx context.Context, f Emitter, timeout time.Duration) error {
+	var done = watchStop(ctx, c.stop)
+	defer done()
+
+	var err = reconnectLoop(f, c.Backoff, c.stop,
+		func() error {
+			var conn = c.Conn()
+			if conn == nil {
+				return c.BNCSConn.RunServer(f, timeout)
+			}
+
+			var stop = watchContext(ctx, func(t time.Time) { conn.SetDeadline(t) })
+			defer stop()
+
+			return c.BNCSConn.RunServer(f, timeout)
+		},
+		func() error { return dialNetConn(c.Dialer, c.SetConn) },
+	)
+
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// RunClientContext runs like RunClient, reconnecting via Dialer on recoverable errors, but also
+// forces the in-flight attempt to return early and stops reconnecting once ctx is done,
+// returning nil (rather than ctx.Err()) in that case, same as BNCSConn.RunClientContext
+func (c *ReconnectingBNCSConn) RunClientContext(ctx context.Context, f Emitter, timeout time.Duration) error {
+	var done = watchStop(ctx, c.stop)
+	defer done()
+
+	var err = reconnectLoop(f, c.Backoff, c.stop,
+		func() error {
+			var conn = c.Conn()
+			if conn == nil {
+				return c.BNCSConn.RunClient(f, timeout)
+			}
+
+			var stop = watchContext(ctx, func(t time.Time) { conn.SetDeadline(t) })
+			defer stop()
+
+			return c.BNCSConn.RunClient(f, timeout)
+		},
+		func() error { return dialNetConn(c.Dialer, c.SetConn) },
+	)
+
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// Stop prevents further reconnect attempts and closes the underlying connection
+func (c *ReconnectingBNCSConn) Stop() {
+	closeStop(c.stop)
+	c.Close()
+}
+
+// ReconnectingCAPIConn wraps a CAPIConn and automatically re-dials the underlying websocket.Conn
+// via Dialer whenever Run fails with a recoverable error, using exponential backoff. Handlers
+// and interceptors registered on the embedded CAPIConn are preserved across reconnects, since
+// only the underlying websocket.Conn is swapped via SetConn.
+type ReconnectingCAPIConn struct {
+	*CAPIConn
+
+	// Dialer opens a new underlying websocket.Conn when a reconnect is needed
+	Dialer func() (*websocket.Conn, error)
+
+	// Backoff configures delay between reconnect attempts
+	Backoff Backoff
+
+	stop chan struct{}
+}
+
+// NewReconnectingCAPIConn returns conn wrapped in a ReconnectingCAPIConn that re-dials using dialer
+func NewReconnectingCAPIConn(conn *websocket.Conn, dialer func() (*websocket.Conn, error)) *ReconnectingCAPIConn {
+	return &ReconnectingCAPIConn{
+		CAPIConn: NewCAPIConn(conn),
+		Dialer:   dialer,
+		Backoff:  DefaultBackoff,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Run reads packets from Conn like CAPIConn.Run, reconnecting via Dialer on recoverable errors
+// until Stop is called or a non-recoverable error occurs
+func (c *ReconnectingCAPIConn) Run(f Emitter, timeout time.Duration) error {
+	return reconnectLoop(f, c.Backoff, c.stop,
+		func() error { return c.CAPIConn.Run(f, timeout) },
+		func() error {
+			conn, err := c.Dialer()
+			if err != nil {
+				return err
+			}
+
+			if isSelfConnect(conn.LocalAddr(), conn.RemoteAddr()) {
+				conn.Close()
+				return ErrSelfConnect
+			}
+
+			c.SetConn(conn)
+			return nil
+		},
+	)
+}
+
+// RunContext runs like Run, reconnecting via Dialer on recoverable errors, but also forces the
+// in-flight attempt to return early and stops reconnecting once ctx is done, returning nil
+// (rather than ctx.Err()) in that case, same as CAPIConn.RunContext
+func (c *ReconnectingCAPIConn) RunContext(ctx context.Context, f Emitter, timeout time.Duration) error {
+	var done = watchStop(ctx, c.stop)
+	defer done()
+
+	var err = reconnectLoop(f, c.Backoff, c.stop,
+		func() error {
+			var conn = c.Conn()
+			if conn == nil {
+				return c.CAPIConn.Run(f, timeout)
+			}
+
+			var stop = watchContext(ctx, func(t time.Time) {
+				conn.SetReadDeadline(t)
+				conn.SetWriteDeadline(t)
+			})
+			defer stop()
+
+			return c.CAPIConn.Run(f, timeout)
+		},
+		func() error {
+			conn, derr := c.Dialer()
+			if derr != nil {
+				return derr
+			}
+
+			if isSelfConnect(conn.LocalAddr(), conn.RemoteAddr()) {
+				conn.Close()
+				return ErrSelfConnect
+			}
+
+			c.SetConn(conn)
+			return nil
+		},
+	)
+
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// Stop prevents further reconnect attempts and closes the underlying connection
+func (c *ReconnectingCAPIConn) Stop() {
+	closeStop(c.stop)
+	c.Close()
+}